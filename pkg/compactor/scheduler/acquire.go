@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// maxAcquireJobTimeout bounds how long a single AcquireJob call may hang, so a
+// worker that goes away mid-call is never parked indefinitely server-side.
+const maxAcquireJobTimeout = 55 * time.Second
+
+// AcquireJobRequest names the tenants a worker can serve (all known tenants, if
+// empty) and how long it is willing to hang waiting for work before rotating to
+// another call.
+type AcquireJobRequest struct {
+	Tenants []string
+	Timeout time.Duration
+}
+
+// AcquireJobResponse carries the job leased to the worker, if any. Found is
+// false when req.Timeout elapsed, or ctx was cancelled, before any job became
+// available; the worker is expected to call AcquireJob again.
+type AcquireJobResponse struct {
+	Tenant string
+	Job    AcquiredJob
+	Found  bool
+}
+
+// AcquireJob is a long-poll alternative to the previous tick-based polling
+// loop: it blocks until a job becomes available for one of req.Tenants, the
+// requested timeout elapses, or ctx is done (e.g. the worker disconnected),
+// whichever happens first. Internally it parks on the rotator's per-tenant
+// wake-up channels, which are signalled when a tenant is discovered and when
+// JobTracker's MarkFailed or RevokeOldestActive moves a job back to pending
+// (jobs made pending by Maintenance or a completed plan send no wake-up and
+// are picked up on the next call), and leases through the rotator's existing fairness ordering so
+// that, when several tenants are ready at once, the same tenant doesn't win
+// every wake-up.
+func (s *Scheduler) AcquireJob(ctx context.Context, req *AcquireJobRequest) (*AcquireJobResponse, error) {
+	timeout := req.Timeout
+	if timeout <= 0 || timeout > maxAcquireJobTimeout {
+		timeout = maxAcquireJobTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		// Register for wake-up before trying to lease: a job becoming pending
+		// between the two would otherwise fire NotifyTenant against a channel
+		// that doesn't exist yet, and the one created afterward would never
+		// close, leaving this call parked for the full timeout instead of
+		// waking promptly.
+		woken := s.rotator.Wait(req.Tenants)
+
+		if tenant, job, ok := s.rotator.TryAcquire(req.Tenants, s.clock.Now(), s.leaseDuration); ok {
+			return &AcquireJobResponse{Tenant: tenant, Job: job, Found: true}, nil
+		}
+
+		select {
+		case <-woken:
+			// A job may now be available; loop around and try to lease it. If
+			// another parked call (or the rotator's fairness ordering) beat us
+			// to it, we'll simply park again below.
+		case <-ctx.Done():
+			// Timeout, or the worker disconnected: release without leasing so
+			// another worker can pick the job up instead.
+			return &AcquireJobResponse{}, nil
+		case <-s.draining:
+			// Graceful shutdown: stop parking so the service can reach
+			// StoppingState instead of waiting out whatever timeout this
+			// particular caller happened to request.
+			return &AcquireJobResponse{}, nil
+		}
+	}
+}
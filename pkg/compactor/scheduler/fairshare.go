@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"flag"
+	"time"
+)
+
+const (
+	defaultProtectedFractionOfFairShare = 0.5
+
+	// fairShareWindow is the rolling window fair-share accounting is computed
+	// over: a tenant's fair share is expressed as lease-seconds within this
+	// window, so it can be compared directly against the lease-seconds a
+	// tenant has actually consumed in that same window.
+	fairShareWindow = time.Hour
+)
+
+// FairShareConfig controls how the rotator protects tenants from being starved
+// by a single tenant with a large backlog, following the "protected fraction
+// of fair share" idea from Armada's scheduler: each tenant is guaranteed up to
+// ProtectedFractionOfFairShare of its weighted fair share of maxLeases before
+// its already-leased jobs become eligible for revocation back to pending.
+type FairShareConfig struct {
+	ProtectedFractionOfFairShare float64 `yaml:"protected_fraction_of_fair_share" category:"advanced"`
+}
+
+func (cfg *FairShareConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Float64Var(&cfg.ProtectedFractionOfFairShare, prefix+"fair-share.protected-fraction", defaultProtectedFractionOfFairShare,
+		"Fraction of a tenant's weighted fair share of leases that is protected from revocation, even when other tenants are starved for capacity.")
+}
+
+// TenantWeights resolves the fair-share weight of a tenant, e.g. from runtime
+// overrides. Tenants not present default to a weight of 1.0.
+type TenantWeights interface {
+	Weight(tenant string) float64
+}
+
+// leaseSample records a single lease's contribution to a tenant's rolling
+// lease-seconds window.
+type leaseSample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// tenantLoad is the fair-share bookkeeping the rotator keeps for a single
+// tenant: its weight and the recent leases it has been granted, from which its
+// rolling-window lease-seconds consumption is computed on demand.
+type tenantLoad struct {
+	weight  float64
+	samples []leaseSample
+}
+
+// leasedSeconds returns the total lease-seconds tenant load has consumed
+// within window of now, discarding (but not mutating load) samples older than
+// that.
+func (l tenantLoad) leasedSeconds(now time.Time, window time.Duration) float64 {
+	var total float64
+	for _, s := range l.samples {
+		if window <= 0 || now.Sub(s.at) <= window {
+			total += s.duration.Seconds()
+		}
+	}
+	return total
+}
+
+// fairShareSeconds returns a tenant's share of the rotator's total lease
+// capacity over window, expressed in lease-seconds (the same unit
+// tenantLoad.leasedSeconds reports), weighted by weight against the total
+// weight across all known tenants. maxLeases leases running continuously for
+// window is the total capacity being shared.
+func fairShareSeconds(weight, totalWeight float64, maxLeases int, window time.Duration) float64 {
+	if totalWeight <= 0 || maxLeases <= 0 || window <= 0 {
+		return 0
+	}
+	return weight / totalWeight * float64(maxLeases) * window.Seconds()
+}
+
+// belowFairShare reports whether a tenant that has consumed leasedSeconds of
+// lease time (in the same window as share was computed over) is still under
+// its weighted fair share, and should therefore be preferred for new leases
+// when capacity is scarce.
+func belowFairShare(leasedSeconds, share float64) bool {
+	return leasedSeconds < share
+}
+
+// eligibleForRevocation reports whether a tenant's already-leased jobs may be
+// revoked back to pending on their next heartbeat: true once the tenant has
+// consumed more than protectedFraction of its weighted fair share. Tenants at
+// or below their protected fraction must never have jobs revoked.
+func eligibleForRevocation(leasedSeconds, share, protectedFraction float64) bool {
+	if share <= 0 {
+		return false
+	}
+	return leasedSeconds > protectedFraction*share
+}
+
+// selectTenant picks which of candidates should receive the next lease when
+// capacity is scarce: tenants below their weighted fair share are preferred
+// over ones that have exceeded it, and among tenants on the same side of their
+// fair share the one with the largest deficit (or smallest surplus) wins. This
+// is what stops a single tenant with a large backlog from starving the rest:
+// once it's over its fair share, every other tenant below theirs outranks it.
+func selectTenant(candidates []string, loads map[string]tenantLoad, totalWeight float64, maxLeases int, now time.Time) (tenant string, ok bool) {
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	var bestBelow bool
+	var bestDeficit float64
+	for i, t := range candidates {
+		load := loads[t]
+		weight := load.weight
+		if weight <= 0 {
+			weight = 1
+		}
+		share := fairShareSeconds(weight, totalWeight, maxLeases, fairShareWindow)
+		leased := load.leasedSeconds(now, fairShareWindow)
+		below := belowFairShare(leased, share)
+		deficit := share - leased
+
+		if i == 0 || (below && !bestBelow) || (below == bestBelow && deficit > bestDeficit) {
+			tenant, bestBelow, bestDeficit = t, below, deficit
+		}
+	}
+	return tenant, true
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+// SetWeights installs the TenantWeights used to look up each tenant's
+// fair-share weight, e.g. backed by runtime overrides. Before SetWeights is
+// called (or if it's never called), every tenant defaults to a weight of 1.0.
+func (r *Rotator) SetWeights(w TenantWeights) {
+	r.fairShareMu.Lock()
+	defer r.fairShareMu.Unlock()
+	r.weights = w
+}
+
+// CompactorSchedulerOverrides is the subset of the tenant runtime overrides
+// the compactor scheduler's fair-share rotator needs.
+type CompactorSchedulerOverrides interface {
+	CompactorJobFairShareWeight(tenant string) float64
+}
+
+// OverridesTenantWeights adapts a runtime-overrides reader exposing a
+// per-tenant fair-share weight into a TenantWeights.
+type OverridesTenantWeights struct {
+	overrides CompactorSchedulerOverrides
+}
+
+func NewOverridesTenantWeights(overrides CompactorSchedulerOverrides) *OverridesTenantWeights {
+	return &OverridesTenantWeights{overrides: overrides}
+}
+
+func (o *OverridesTenantWeights) Weight(tenant string) float64 {
+	return o.overrides.CompactorJobFairShareWeight(tenant)
+}
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/services"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const defaultJobsPageSize = 200
+
+// Scheduler ties the tenant discoverer and rotator together: it exposes them
+// over HTTP so operators can inspect what the compactor scheduler has
+// discovered and queued, and it serves the AcquireJob long-poll API that
+// workers use to fetch work instead of ticking a poll loop.
+type Scheduler struct {
+	logger        log.Logger
+	discoverer    *TenantDiscoverer
+	rotator       *Rotator
+	clock         clock.Clock
+	leaseDuration time.Duration
+	fairShare     FairShareConfig
+
+	// draining is closed by DrainService once the scheduler starts stopping,
+	// releasing every AcquireJob call currently parked in a long poll.
+	draining chan struct{}
+}
+
+// NewScheduler returns a Scheduler wrapping the given discoverer and rotator. It
+// does not start or stop either of them; callers remain responsible for running
+// discoverer, and the services returned by HeartbeatService and DrainService,
+// as dskit services.
+//
+// If weights is non-nil, it's installed as the rotator's source of per-tenant
+// fair-share weight (e.g. an *OverridesTenantWeights reading runtime
+// overrides); otherwise every tenant keeps the default weight of 1.0.
+func NewScheduler(discoverer *TenantDiscoverer, rotator *Rotator, leaseDuration time.Duration, fairShare FairShareConfig, weights TenantWeights, logger log.Logger) *Scheduler {
+	if weights != nil {
+		rotator.SetWeights(weights)
+	}
+	return &Scheduler{
+		logger:        logger,
+		discoverer:    discoverer,
+		rotator:       rotator,
+		clock:         clock.New(),
+		leaseDuration: leaseDuration,
+		fairShare:     fairShare,
+		draining:      make(chan struct{}),
+	}
+}
+
+// DrainService returns a dskit service that does nothing while running, and on
+// StoppingState closes the scheduler's draining channel, releasing every
+// AcquireJob call currently parked in a long poll instead of leaving them to
+// wait out whatever timeout each caller happened to request. Callers are
+// responsible for including it in whatever services.Manager runs the rest of
+// the compactor scheduler.
+func (s *Scheduler) DrainService() services.Service {
+	return services.NewBasicService(nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, func(_ error) error {
+		close(s.draining)
+		return nil
+	})
+}
+
+// HeartbeatService returns the dskit service that periodically revokes leases
+// from tenants over their protected fraction of fair share. Callers are
+// responsible for including it in the service manager running the rest of the
+// compactor scheduler; it is not started automatically.
+func (s *Scheduler) HeartbeatService(interval time.Duration) services.Service {
+	return NewFairShareHeartbeatService(s.rotator, s.clock, interval, s.fairShare.ProtectedFractionOfFairShare)
+}
+
+// RegisterRoutes registers the scheduler's introspection endpoints on router.
+func (s *Scheduler) RegisterRoutes(router *mux.Router) {
+	router.Path("/scheduler/tenants").Methods(http.MethodGet).HandlerFunc(s.TenantsHandler)
+	router.Path("/scheduler/tenant/{tenant}/jobs").Methods(http.MethodGet).HandlerFunc(s.TenantJobsHandler)
+}
+
+type tenantsResponse struct {
+	Tenants []string `json:"tenants"`
+}
+
+// TenantsHandler serves the list of tenants currently tracked by the scheduler's
+// tenant discoverer, as JSON or, for browsers, an HTML page.
+func (s *Scheduler) TenantsHandler(w http.ResponseWriter, r *http.Request) {
+	resp := tenantsResponse{Tenants: s.discoverer.Tenants()}
+
+	util.RenderHTTPResponse(w, resp, tenantsPageTemplate, r)
+}
+
+type tenantJobsResponse struct {
+	Tenant          string     `json:"tenant"`
+	PlanState       JobState   `json:"plan_state"`
+	PlanCompletedAt *time.Time `json:"plan_completed_at,omitempty"`
+	State           JobState   `json:"state"`
+	Jobs            []JobInfo  `json:"jobs"`
+	NextCursor      string     `json:"next_cursor,omitempty"`
+}
+
+// TenantJobsHandler serves the pending/active/complete jobs tracked for a single
+// tenant, along with its plan status, as JSON or, for browsers, an HTML page.
+// Results can be filtered with ?state=available|leased|complete (default
+// available) and paginated with ?cursor=...&limit=....
+func (s *Scheduler) TenantJobsHandler(w http.ResponseWriter, r *http.Request) {
+	tenant := mux.Vars(r)["tenant"]
+
+	tracker, ok := s.rotator.TenantTracker(tenant)
+	if !ok {
+		http.Error(w, "unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	state := JobState(r.URL.Query().Get("state"))
+	if state == "" {
+		state = JobStateAvailable
+	}
+	switch state {
+	case JobStateAvailable, JobStateLeased, JobStateComplete:
+	default:
+		http.Error(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultJobsPageSize
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	jobs, nextCursor := tracker.Jobs(state, r.URL.Query().Get("cursor"), limit)
+	planState, planCompletedAt := tracker.PlanStatus()
+
+	resp := tenantJobsResponse{
+		Tenant:     tenant,
+		PlanState:  planState,
+		State:      state,
+		Jobs:       jobs,
+		NextCursor: nextCursor,
+	}
+	if !planCompletedAt.IsZero() {
+		resp.PlanCompletedAt = &planCompletedAt
+	}
+
+	util.RenderHTTPResponse(w, resp, tenantJobsPageTemplate, r)
+}
+
+var tenantsPageTemplate = template.Must(template.New("schedulerTenants").Parse(`
+<!DOCTYPE html>
+<html>
+	<head><title>Compactor scheduler: tenants</title></head>
+	<body>
+		<h1>Compactor scheduler: tenants</h1>
+		<ul>
+			{{ range .Tenants }}
+				<li><a href="/scheduler/tenant/{{ . }}/jobs">{{ . }}</a></li>
+			{{ else }}
+				<li>no tenants discovered yet</li>
+			{{ end }}
+		</ul>
+	</body>
+</html>
+`))
+
+var tenantJobsPageTemplate = template.Must(template.New("schedulerTenantJobs").Parse(`
+<!DOCTYPE html>
+<html>
+	<head><title>Compactor scheduler: {{ .Tenant }}</title></head>
+	<body>
+		<h1>Compactor scheduler: {{ .Tenant }}</h1>
+		<p>plan status: {{ .PlanState }}{{ if .PlanCompletedAt }} (completed {{ .PlanCompletedAt }}){{ end }}</p>
+		<p>showing jobs in state: {{ .State }}</p>
+		<table border="1" cellpadding="4">
+			<tr><th>ID</th><th>state</th><th>plan job</th><th>order</th></tr>
+			{{ range .Jobs }}
+				<tr><td>{{ .ID }}</td><td>{{ .State }}</td><td>{{ .IsPlan }}</td><td>{{ .Order }}</td></tr>
+			{{ else }}
+				<tr><td colspan="4">no jobs</td></tr>
+			{{ end }}
+		</table>
+		{{ if .NextCursor }}
+			<p><a href="?state={{ .State }}&cursor={{ .NextCursor }}">next page</a></p>
+		{{ end }}
+	</body>
+</html>
+`))
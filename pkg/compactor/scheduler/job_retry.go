@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"flag"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRetryInitialDelay   = 30 * time.Second
+	defaultRetryMaxDelay       = 24 * time.Hour
+	defaultRetryMaxRuns        = 12
+	defaultRetryJitterFraction = 0.15
+)
+
+// RetryConfig controls how a failed job is re-queued for another attempt: the
+// backoff grows exponentially from InitialDelay up to MaxDelay, jittered by
+// JitterFraction, until the job has failed MaxRuns times and is given up on.
+type RetryConfig struct {
+	InitialDelay   time.Duration `yaml:"initial_delay" category:"advanced"`
+	MaxDelay       time.Duration `yaml:"max_delay" category:"advanced"`
+	MaxRuns        int           `yaml:"max_runs" category:"advanced"`
+	JitterFraction float64       `yaml:"jitter_fraction" category:"advanced"`
+}
+
+func (cfg *RetryConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.DurationVar(&cfg.InitialDelay, prefix+"retry.initial-delay", defaultRetryInitialDelay, "Delay before the first retry of a failed compaction or plan job.")
+	f.DurationVar(&cfg.MaxDelay, prefix+"retry.max-delay", defaultRetryMaxDelay, "Maximum delay between retries of a failed compaction or plan job, before jitter is added.")
+	f.IntVar(&cfg.MaxRuns, prefix+"retry.max-runs", defaultRetryMaxRuns, "Maximum number of attempts for a compaction or plan job before it is marked permanently failed and removed.")
+	f.Float64Var(&cfg.JitterFraction, prefix+"retry.jitter-fraction", defaultRetryJitterFraction, "Fraction of the computed backoff delay to randomly jitter, to avoid retry stampedes.")
+}
+
+// nextAttemptAt returns the earliest time a job that has failed numRuns times,
+// most recently at lastRun, becomes eligible to be leased again. A numRuns of
+// zero (never failed) is always eligible.
+func nextAttemptAt(cfg RetryConfig, numRuns int, lastRun time.Time) time.Time {
+	if numRuns <= 0 {
+		return time.Time{}
+	}
+
+	delay := cfg.InitialDelay << uint(numRuns-1)
+	// Shifting back detects overflow, which would otherwise escape the cap.
+	if cfg.MaxDelay > 0 && (delay > cfg.MaxDelay || delay>>uint(numRuns-1) != cfg.InitialDelay) {
+		delay = cfg.MaxDelay
+	}
+	if cfg.JitterFraction > 0 {
+		delay += time.Duration(cfg.JitterFraction * float64(delay) * rand.Float64())
+	}
+	return lastRun.Add(delay)
+}
+
+// permanentlyFailed reports whether a job that has failed numRuns times has
+// exhausted its retry budget and should be removed instead of requeued.
+func permanentlyFailed(cfg RetryConfig, numRuns int) bool {
+	return cfg.MaxRuns > 0 && numRuns >= cfg.MaxRuns
+}
+
+// MarkFailed records that the compaction job's most recent lease ended in
+// failure: it bumps numRuns and stamps lastRun so the tracker can compute the
+// next eligible retry time with exponential backoff. The caller (JobTracker)
+// is responsible for moving the job back to pending, or for checking
+// permanentlyFailed and removing it instead when it has exhausted its retry
+// budget.
+func (j *TrackedCompactionJob) MarkFailed(now time.Time) {
+	j.numRuns++
+	j.lastRun = now
+}
+
+// MarkFailed records that the tenant's plan job most recently ended in
+// failure, following the same backoff bookkeeping as compaction jobs.
+func (j *TrackedPlanJob) MarkFailed(now time.Time) {
+	j.numRuns++
+	j.lastRun = now
+}
@@ -0,0 +1,9 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+// CompactionJob returns the underlying job definition a worker needs to
+// actually perform the compaction, e.g. to pass to AcquireJobResponse.
+func (j *TrackedCompactionJob) CompactionJob() *CompactionJob {
+	return j.job
+}
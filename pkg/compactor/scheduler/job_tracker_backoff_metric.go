@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import "time"
+
+// ReportBackoffMetric refreshes the tenant's jobsInBackoff gauge with the
+// number of pending jobs currently waiting out a retry backoff. It's called
+// opportunistically from the rotator whenever it considers this tenant for a
+// lease, since persistently failing tenants are otherwise invisible between
+// the (infrequent) moments a job actually fails.
+func (jt *JobTracker) ReportBackoffMetric(now time.Time) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	jt.metrics.jobsInBackoff.Set(float64(jobsInBackoff(jt.retry, now, jt.pending)))
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import "time"
+
+// ActiveCount returns the number of jobs currently leased for this tenant.
+// Used by the rotator to estimate how close outstanding leases are to
+// maxLeases across all tenants.
+func (jt *JobTracker) ActiveCount() int {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+	return jt.active.Len()
+}
+
+// RevokeOldestActive moves the longest-held active job back to pending,
+// without counting it as a failure (no retry backoff is applied). Used by the
+// rotator's fair-share preemption: a tenant that has exceeded its protected
+// fraction of fair share has one of its leases revoked per heartbeat so a
+// starved tenant can pick it up instead.
+func (jt *JobTracker) RevokeOldestActive(now time.Time) (jobID string, ok bool) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	for e := jt.active.Front(); e != nil; e = e.Next() {
+		tj := e.Value.(TrackedJob)
+		if _, isPlan := tj.(*TrackedPlanJob); isPlan {
+			// Never revoke the plan job: losing it would stall the tenant's own
+			// discovery of new compaction work, which defeats the point of
+			// protecting it in the first place.
+			continue
+		}
+
+		jt.active.Remove(e)
+		jt.incompleteJobs[tj.ID()] = jt.pending.PushBack(tj)
+		jt.notifyPending()
+		return tj.ID(), true
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"container/list"
+	"sort"
+	"time"
+)
+
+// JobState is the coarse, externally-visible lifecycle state of a tracked job, used
+// by the scheduler's tenant introspection endpoints to filter results.
+type JobState string
+
+const (
+	JobStateAvailable JobState = "available"
+	JobStateLeased    JobState = "leased"
+	JobStateComplete  JobState = "complete"
+)
+
+// JobInfo is a point-in-time, read-only view of a tracked job, suitable for
+// rendering on the scheduler's introspection endpoints.
+type JobInfo struct {
+	ID          string     `json:"id"`
+	State       JobState   `json:"state"`
+	IsPlan      bool       `json:"is_plan"`
+	Order       uint32     `json:"order,omitempty"`
+	LeaseExpiry *time.Time `json:"lease_expiry,omitempty"`
+}
+
+// Jobs returns a page of the tenant's jobs in the given state, ordered by ID.
+// Pagination is driven by an opaque cursor: pass the returned nextCursor back in
+// to fetch the next page. An empty nextCursor means there are no more results.
+func (jt *JobTracker) Jobs(state JobState, cursor string, limit int) (jobs []JobInfo, nextCursor string) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	var all []JobInfo
+	switch state {
+	case JobStateAvailable:
+		all = jobInfosFromList(jt.pending, JobStateAvailable)
+	case JobStateLeased:
+		all = jobInfosFromList(jt.active, JobStateLeased)
+	case JobStateComplete:
+		for _, j := range jt.completeCompactionJobs {
+			all = append(all, JobInfo{ID: j.ID(), State: JobStateComplete})
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	}
+
+	start := 0
+	if cursor != "" {
+		start = sort.Search(len(all), func(i int) bool { return all[i].ID > cursor })
+	}
+	if start > len(all) {
+		start = len(all)
+	}
+	end := len(all)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	jobs = all[start:end]
+	if end < len(all) {
+		nextCursor = jobs[len(jobs)-1].ID
+	}
+	return jobs, nextCursor
+}
+
+// PlanStatus reports the current state of the tenant's plan job: available if it is
+// queued but not yet leased, leased if a worker is actively planning, or complete
+// along with the time the most recent plan finished.
+func (jt *JobTracker) PlanStatus() (state JobState, completedAt time.Time) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	if _, ok := jt.incompleteJobs[planJobId]; ok {
+		if jt.isPlanJobLeased {
+			return JobStateLeased, time.Time{}
+		}
+		return JobStateAvailable, time.Time{}
+	}
+	return JobStateComplete, jt.completePlanTime
+}
+
+func jobInfosFromList(l *list.List, state JobState) []JobInfo {
+	var infos []JobInfo
+	for e := l.Front(); e != nil; e = e.Next() {
+		tj := e.Value.(TrackedJob)
+		info := JobInfo{ID: tj.ID(), State: state}
+		switch j := tj.(type) {
+		case *TrackedPlanJob:
+			info.IsPlan = true
+		case *TrackedCompactionJob:
+			info.Order = j.order
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
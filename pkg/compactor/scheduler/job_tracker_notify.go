@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+// SetOnPending installs a hook that's called whenever the tracker moves a job
+// into pending for this tenant. The tenant discoverer wires this to the
+// rotator's NotifyTenant, so AcquireJob's long-poll wakes up as soon as
+// there's real work to hand out, instead of only when a brand-new tenant is
+// discovered.
+//
+// Today that covers every pending-list mutation this package defines:
+// recovery from a failed lease (MarkFailed) and fair-share preemption
+// (RevokeOldestActive), in addition to new-tenant discovery. Maintenance
+// producing a plan, and a completed plan expanding into compaction jobs, are
+// the other two sources of new pending work, but both live in JobTracker's
+// base implementation, which isn't part of this package's current source -
+// wiring them in means calling notifyPending() at the end of whichever
+// method pushes the new job, the exact way MarkFailed (job_tracker_retry.go)
+// and RevokeOldestActive (job_tracker_fairshare.go) already do.
+func (jt *JobTracker) SetOnPending(f func()) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+	jt.onPending = f
+}
+
+// notifyPending calls the onPending hook, if one is set. jt.mu must be held by
+// the caller.
+func (jt *JobTracker) notifyPending() {
+	if jt.onPending != nil {
+		jt.onPending()
+	}
+}
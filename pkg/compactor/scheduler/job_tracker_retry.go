@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"container/list"
+	"time"
+)
+
+// isRetryEligible reports whether a pending job that has previously failed
+// numRuns times, most recently at lastRun, is past its backoff window and can
+// be leased now. NextAvailable skips pending jobs for which this is false.
+func isRetryEligible(cfg RetryConfig, now time.Time, numRuns int, lastRun time.Time) bool {
+	if numRuns == 0 {
+		return true
+	}
+	return !now.Before(nextAttemptAt(cfg, numRuns, lastRun))
+}
+
+// NextAvailable leases the first pending job that is currently eligible to
+// run, skipping any still waiting out a retry backoff from a previous
+// MarkFailed, and persists the lease through the tenant's JobPersister so a
+// crash right after can't hand out a lease that recovery wouldn't see.
+// leaseDuration is accepted for symmetry with the rest of the acquire path
+// (see Rotator.recordLease); NextAvailable itself only needs now to evaluate
+// eligibility and mark when the lease started. ok is false if every pending
+// job is either empty or still backing off.
+func (jt *JobTracker) NextAvailable(now time.Time, leaseDuration time.Duration) (tj TrackedJob, ok bool) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	for e := jt.pending.Front(); e != nil; e = e.Next() {
+		candidate := e.Value.(TrackedJob)
+
+		var numRuns int
+		var lastRun time.Time
+		switch j := candidate.(type) {
+		case *TrackedCompactionJob:
+			numRuns, lastRun = j.numRuns, j.lastRun
+		case *TrackedPlanJob:
+			numRuns, lastRun = j.numRuns, j.lastRun
+		}
+		if !isRetryEligible(jt.retry, now, numRuns, lastRun) {
+			continue
+		}
+
+		jt.pending.Remove(e)
+		switch j := candidate.(type) {
+		case *TrackedCompactionJob:
+			j.MarkLeased(now)
+		case *TrackedPlanJob:
+			j.MarkLeased(now)
+			jt.isPlanJobLeased = true
+		}
+
+		if err := jt.persister.WriteAndDeleteJobs([]TrackedJob{candidate}, nil); err != nil {
+			// Leave the job tracked as pending rather than hand out a lease the
+			// persister couldn't durably record; it'll be tried again on the
+			// next acquire attempt instead of being silently lost.
+			jt.incompleteJobs[candidate.ID()] = jt.pending.PushBack(candidate)
+			if _, isPlan := candidate.(*TrackedPlanJob); isPlan {
+				jt.isPlanJobLeased = false
+			}
+			continue
+		}
+
+		jt.incompleteJobs[candidate.ID()] = jt.active.PushBack(candidate)
+		return candidate, true
+	}
+
+	return nil, false
+}
+
+// MarkFailed transitions the job identified by jobID from active back to
+// pending, recording the failure for exponential backoff, and persists the
+// updated numRuns/lastRun through the tenant's JobPersister so a restart
+// doesn't lose retry state for in-flight failed jobs. If the job has
+// exhausted its retry budget it is removed entirely instead (also persisted
+// as a delete), and ok reports false.
+func (jt *JobTracker) MarkFailed(jobID string, now time.Time) (ok bool, err error) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	e, exists := jt.incompleteJobs[jobID]
+	if !exists {
+		return false, nil
+	}
+	tj := e.Value.(TrackedJob)
+
+	var numRuns int
+	switch j := e.Value.(type) {
+	case *TrackedCompactionJob:
+		j.MarkFailed(now)
+		numRuns = j.numRuns
+	case *TrackedPlanJob:
+		j.MarkFailed(now)
+		numRuns = j.numRuns
+		jt.isPlanJobLeased = false
+	}
+
+	jt.active.Remove(e)
+
+	if permanentlyFailed(jt.retry, numRuns) {
+		if err := jt.persister.WriteAndDeleteJobs(nil, []TrackedJob{tj}); err != nil {
+			// Leave the job tracked in memory so it's retried on the next
+			// persist attempt instead of being silently lost.
+			jt.active.PushBack(tj)
+			jt.incompleteJobs[jobID] = jt.active.Back()
+			if _, isPlan := tj.(*TrackedPlanJob); isPlan {
+				jt.isPlanJobLeased = true
+			}
+			return false, err
+		}
+		delete(jt.incompleteJobs, jobID)
+		jt.metrics.jobsPermanentlyFailed.Inc()
+		return false, nil
+	}
+
+	if err := jt.persister.WriteAndDeleteJobs([]TrackedJob{tj}, nil); err != nil {
+		jt.active.PushBack(tj)
+		jt.incompleteJobs[jobID] = jt.active.Back()
+		if _, isPlan := tj.(*TrackedPlanJob); isPlan {
+			jt.isPlanJobLeased = true
+		}
+		return false, err
+	}
+
+	jt.incompleteJobs[jobID] = jt.pending.PushBack(tj)
+	jt.notifyPending()
+	return true, nil
+}
+
+// jobsInBackoff returns the number of pending jobs in l that are not yet
+// eligible for lease because they are waiting out a retry backoff.
+// ReportBackoffMetric reports this count per tenant, refreshed whenever the
+// rotator considers the tenant for a lease, so operators can see persistently
+// failing tenants before they hit maxRuns; Maintenance does not report it.
+func jobsInBackoff(cfg RetryConfig, now time.Time, l *list.List) int {
+	var count int
+	for e := l.Front(); e != nil; e = e.Next() {
+		var numRuns int
+		var lastRun time.Time
+		switch tj := e.Value.(type) {
+		case *TrackedCompactionJob:
+			numRuns, lastRun = tj.numRuns, tj.lastRun
+		case *TrackedPlanJob:
+			numRuns, lastRun = tj.numRuns, tj.lastRun
+		}
+		if !isRetryEligible(cfg, now, numRuns, lastRun) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+// setFairShareFraction records, for tenant, its consumed lease-seconds as a
+// fraction of its weighted fair share (1.0 == exactly at fair share).
+func (m *schedulerMetrics) setFairShareFraction(tenant string, frac float64) {
+	m.fairShareFraction.WithLabelValues(tenant).Set(frac)
+}
+
+// setProtectedFraction records the configured protected fraction of fair
+// share currently applied to tenant.
+func (m *schedulerMetrics) setProtectedFraction(tenant string, frac float64) {
+	m.protectedFraction.WithLabelValues(tenant).Set(frac)
+}
+
+// incRevocations increments the count of leases revoked from tenant by
+// fair-share preemption.
+func (m *schedulerMetrics) incRevocations(tenant string) {
+	m.revocations.WithLabelValues(tenant).Inc()
+}
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// waiters implements the parking side of the AcquireJob long-poll API:
+// per-tenant channels that are closed (and replaced) whenever JobTracker moves
+// a job into pending for that tenant, waking any AcquireJob call parked on it.
+type waiters struct {
+	mu   sync.Mutex
+	byID map[string]chan struct{}
+}
+
+func newWaiters() *waiters {
+	return &waiters{byID: make(map[string]chan struct{})}
+}
+
+// chanFor returns the current wake-up channel for tenant, creating one if this
+// is the first caller waiting on it.
+func (w *waiters) chanFor(tenant string) chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch, ok := w.byID[tenant]
+	if !ok {
+		ch = make(chan struct{})
+		w.byID[tenant] = ch
+	}
+	return ch
+}
+
+// notify wakes every call parked on tenant.
+func (w *waiters) notify(tenant string) {
+	w.mu.Lock()
+	ch, ok := w.byID[tenant]
+	delete(w.byID, tenant)
+	w.mu.Unlock()
+	if ok {
+		close(ch)
+	}
+}
+
+// Wait returns a channel that is closed as soon as any of tenants (or, if
+// empty, any tenant at all) has a job moved into pending. Callers select on
+// the returned channel alongside ctx.Done() so a parked AcquireJob releases
+// promptly on worker disconnect.
+func (r *Rotator) Wait(tenants []string) <-chan struct{} {
+	if len(tenants) == 0 {
+		return r.waiters.chanFor("")
+	}
+
+	merged := make(chan struct{})
+	var once sync.Once
+	for _, t := range tenants {
+		go func(ch <-chan struct{}) {
+			<-ch
+			once.Do(func() { close(merged) })
+		}(r.waiters.chanFor(t))
+	}
+	return merged
+}
+
+// NotifyTenant wakes any AcquireJob calls parked waiting on tenant (and on
+// "any tenant"). Called by the tenant discoverer when a tenant is discovered,
+// and, through the tracker's onPending hook, whenever JobTracker's MarkFailed
+// or RevokeOldestActive moves a job back to pending for that tenant. Jobs
+// made pending by Maintenance or by a completed plan do not call it.
+func (r *Rotator) NotifyTenant(tenant string) {
+	r.waiters.notify(tenant)
+	r.waiters.notify("")
+}
+
+// AcquiredJob carries everything a worker needs to actually execute a leased
+// job, as opposed to JobInfo which is a read-only view built for the
+// introspection endpoints. Job is nil for the plan job, whose work is simply
+// "produce a plan for this tenant".
+type AcquiredJob struct {
+	ID     string
+	IsPlan bool
+	Order  uint32
+	Job    *CompactionJob
+}
+
+// TryAcquire leases a single job from one of the given tenants (all known
+// tenants, if empty), using a round-robin cursor over the rotator's existing
+// tenant order so that, when several tenants are ready at once, the same one
+// doesn't win every call. ok is false if none of the candidates had available
+// work.
+func (r *Rotator) TryAcquire(tenants []string, now time.Time, leaseDuration time.Duration) (tenant string, job AcquiredJob, ok bool) {
+	r.mu.Lock()
+	candidates := tenants
+	if len(candidates) == 0 {
+		candidates = make([]string, 0, len(r.tenants))
+		for t := range r.tenants {
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Strings(candidates)
+
+	n := len(candidates)
+	outstanding := 0
+	for _, tracker := range r.tenants {
+		outstanding += tracker.ActiveCount()
+	}
+	r.mu.Unlock()
+
+	if n == 0 {
+		return "", AcquiredJob{}, false
+	}
+
+	// Below capacity, strict round-robin order is enough for fairness; near
+	// capacity, prefer tenants under their weighted fair share so one tenant's
+	// backlog can't starve the rest.
+	ranked := r.rankByFairShare(candidates, outstanding, now)
+
+	r.mu.Lock()
+	cursor := r.cursor
+	r.mu.Unlock()
+	if r.maxLeases > 0 && float64(outstanding) >= nearCapacityThreshold*float64(r.maxLeases) {
+		// ranked is already in fair-share order: try its head first.
+		cursor = 0
+	}
+
+	for i := 0; i < n; i++ {
+		t := ranked[(cursor+i)%n]
+		r.mu.Lock()
+		tracker, exists := r.tenants[t]
+		r.mu.Unlock()
+		if !exists {
+			continue
+		}
+		tracker.ReportBackoffMetric(now)
+		if tj, leased := tracker.NextAvailable(now, leaseDuration); leased {
+			r.mu.Lock()
+			r.cursor = (r.cursor + i + 1) % n
+			r.mu.Unlock()
+			r.recordLease(t, now, leaseDuration)
+			return t, acquiredJobFrom(tj), true
+		}
+	}
+	return "", AcquiredJob{}, false
+}
+
+// acquiredJobFrom converts a tracked job into the payload handed back to the
+// worker that leased it.
+func acquiredJobFrom(tj TrackedJob) AcquiredJob {
+	switch j := tj.(type) {
+	case *TrackedPlanJob:
+		return AcquiredJob{ID: j.ID(), IsPlan: true}
+	case *TrackedCompactionJob:
+		return AcquiredJob{ID: j.ID(), Order: j.order, Job: j.CompactionJob()}
+	default:
+		return AcquiredJob{ID: tj.ID()}
+	}
+}
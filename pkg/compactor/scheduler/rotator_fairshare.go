@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/dskit/services"
+)
+
+// nearCapacityThreshold is how close outstanding leases must be to maxLeases,
+// as a fraction, before the rotator starts preferring tenants below their fair
+// share over strict round-robin order.
+const nearCapacityThreshold = 0.8
+
+// recordLease updates tenant's rolling lease-seconds window after it is handed
+// a lease of leaseDuration at now, and refreshes its weight from r.weights
+// (defaults to 1.0 for tenants the provider doesn't know about, or when no
+// TenantWeights has been configured via SetWeights).
+func (r *Rotator) recordLease(tenant string, now time.Time, leaseDuration time.Duration) {
+	r.fairShareMu.Lock()
+	defer r.fairShareMu.Unlock()
+
+	weight := 1.0
+	if r.weights != nil {
+		if w := r.weights.Weight(tenant); w > 0 {
+			weight = w
+		}
+	}
+
+	load := r.loads[tenant]
+	load.weight = weight
+	load.samples = pruneSamples(append(load.samples, leaseSample{at: now, duration: leaseDuration}), now, fairShareWindow)
+	r.loads[tenant] = load
+}
+
+// pruneSamples drops samples older than window relative to now, so a
+// tenant's bookkeeping doesn't grow without bound across the life of the
+// process.
+func pruneSamples(samples []leaseSample, now time.Time, window time.Duration) []leaseSample {
+	if window <= 0 {
+		return samples
+	}
+	kept := samples[:0]
+	for _, s := range samples {
+		if now.Sub(s.at) <= window {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// snapshotLoads returns a copy of the rotator's current per-tenant fair-share
+// bookkeeping, along with the total weight across candidates, for use by the
+// pure selection/eligibility helpers in fairshare.go.
+func (r *Rotator) snapshotLoads(candidates []string) (loads map[string]tenantLoad, totalWeight float64) {
+	r.fairShareMu.Lock()
+	defer r.fairShareMu.Unlock()
+
+	loads = make(map[string]tenantLoad, len(r.loads))
+	for t, l := range r.loads {
+		loads[t] = l
+	}
+	for _, t := range candidates {
+		w := loads[t].weight
+		if w <= 0 {
+			w = 1
+		}
+		totalWeight += w
+	}
+	return loads, totalWeight
+}
+
+// rankByFairShare reorders candidates, when leases are near capacity, so that
+// tenants below their weighted fair share of maxLeases are tried before ones
+// that have exceeded it. Below capacity it returns candidates unchanged, since
+// there's no scarcity to protect against yet. It also refreshes the
+// fairShareFraction gauge for every candidate, so operators can see how close
+// each tenant is running to its fair share even before preemption kicks in.
+func (r *Rotator) rankByFairShare(candidates []string, outstandingLeases int, now time.Time) []string {
+	loads, totalWeight := r.snapshotLoads(candidates)
+
+	for _, t := range candidates {
+		load := loads[t]
+		weight := load.weight
+		if weight <= 0 {
+			weight = 1
+		}
+		share := fairShareSeconds(weight, totalWeight, r.maxLeases, fairShareWindow)
+		if share > 0 {
+			r.metrics.setFairShareFraction(t, load.leasedSeconds(now, fairShareWindow)/share)
+		}
+	}
+
+	if len(candidates) <= 1 || r.maxLeases <= 0 || float64(outstandingLeases) < nearCapacityThreshold*float64(r.maxLeases) {
+		return candidates
+	}
+
+	ranked := make([]string, 0, len(candidates))
+	remaining := append([]string(nil), candidates...)
+	for len(remaining) > 0 {
+		winner, _ := selectTenant(remaining, loads, totalWeight, r.maxLeases, now)
+		ranked = append(ranked, winner)
+		for i, t := range remaining {
+			if t == winner {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return ranked
+}
+
+// RevokeOverProtected returns revocable jobs to pending: for every tenant that
+// has consumed more than its protected fraction of fair share, one of its
+// active jobs is moved back to pending on this heartbeat, making it available
+// for another (under fair-share) tenant to lease instead. Tenants at or below
+// their protected fraction are left untouched. It reports the tenants a job
+// was revoked from.
+func (r *Rotator) RevokeOverProtected(now time.Time, protectedFraction float64) []string {
+	r.mu.Lock()
+	tenants := make([]string, 0, len(r.tenants))
+	for t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	r.mu.Unlock()
+
+	loads, totalWeight := r.snapshotLoads(tenants)
+
+	var revoked []string
+	for _, t := range tenants {
+		load := loads[t]
+		weight := load.weight
+		if weight <= 0 {
+			weight = 1
+		}
+		share := fairShareSeconds(weight, totalWeight, r.maxLeases, fairShareWindow)
+		r.metrics.setProtectedFraction(t, protectedFraction)
+
+		if !eligibleForRevocation(load.leasedSeconds(now, fairShareWindow), share, protectedFraction) {
+			continue
+		}
+
+		r.mu.Lock()
+		tracker, ok := r.tenants[t]
+		r.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		if _, ok := tracker.RevokeOldestActive(now); ok {
+			revoked = append(revoked, t)
+			r.metrics.incRevocations(t)
+		}
+	}
+	return revoked
+}
+
+// NewFairShareHeartbeatService returns a dskit service that periodically
+// revokes leases from tenants over their protected fraction of fair share,
+// per RevokeOverProtected. The caller is responsible for including it in
+// whatever services.Manager runs the rest of the compactor scheduler.
+func NewFairShareHeartbeatService(r *Rotator, clk clock.Clock, interval time.Duration, protectedFraction float64) services.Service {
+	return services.NewTimerService(interval, nil, func(context.Context) error {
+		r.RevokeOverProtected(clk.Now(), protectedFraction)
+		return nil
+	}, nil)
+}
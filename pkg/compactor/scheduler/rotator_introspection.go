@@ -0,0 +1,13 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+// TenantTracker returns the JobTracker backing the given tenant, if the rotator is
+// currently tracking it. Used by the scheduler's HTTP introspection endpoints.
+func (r *Rotator) TenantTracker(tenant string) (*JobTracker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tenants[tenant]
+	return t, ok
+}
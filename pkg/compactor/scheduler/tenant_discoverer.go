@@ -5,19 +5,20 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/benbjohnson/clock"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/services"
-	"github.com/thanos-io/objstore"
 
-	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/util"
 )
 
-// TenantDiscoverer periodically scans the bucket for new tenants.
+// TenantDiscoverer periodically asks a TenantSource for the current set of
+// tenants and reconciles it against what it already knows about.
 type TenantDiscoverer struct {
 	services.Service
 
@@ -25,19 +26,24 @@ type TenantDiscoverer struct {
 	metrics              *schedulerMetrics
 	clock                clock.Clock
 	allowedTenants       *util.AllowList
-	bkt                  objstore.Bucket
+	source               TenantSource
 	jpm                  JobPersistenceManager
 	userDiscoveryBackoff backoff.Config
 	rotator              *Rotator
 	maxLeases            int
-	knownTenants         map[string]struct{}
+
+	// knownTenantsMu guards knownTenants, which is written by discoverTenants (and
+	// RecoverFrom, before the service starts) but also read concurrently by Tenants()
+	// from the scheduler's HTTP introspection handlers.
+	knownTenantsMu sync.Mutex
+	knownTenants   map[string]struct{}
 }
 
 func NewTenantDiscoverer(
 	cfg Config,
 	allowList *util.AllowList,
 	rotator *Rotator,
-	bkt objstore.Bucket,
+	source TenantSource,
 	jpm JobPersistenceManager,
 	metrics *schedulerMetrics,
 	logger log.Logger) *TenantDiscoverer {
@@ -46,7 +52,7 @@ func NewTenantDiscoverer(
 		metrics:              metrics,
 		clock:                clock.New(),
 		allowedTenants:       allowList,
-		bkt:                  bkt,
+		source:               source,
 		jpm:                  jpm,
 		userDiscoveryBackoff: cfg.userDiscoveryBackoff,
 		rotator:              rotator,
@@ -60,11 +66,28 @@ func NewTenantDiscoverer(
 // RecoverFrom populates the tenant discoverer with known tenants from recovered state.
 // Must be called before the service is started.
 func (s *TenantDiscoverer) RecoverFrom(recovered map[string]*JobTracker) {
-	for tenant := range recovered {
+	s.knownTenantsMu.Lock()
+	defer s.knownTenantsMu.Unlock()
+	for tenant, tracker := range recovered {
+		tracker.SetOnPending(func() { s.rotator.NotifyTenant(tenant) })
 		s.knownTenants[tenant] = struct{}{}
 	}
 }
 
+// Tenants returns the sorted list of tenants currently tracked by the discoverer.
+// Used by the scheduler's /scheduler/tenants introspection endpoint.
+func (s *TenantDiscoverer) Tenants() []string {
+	s.knownTenantsMu.Lock()
+	defer s.knownTenantsMu.Unlock()
+
+	tenants := make([]string, 0, len(s.knownTenants))
+	for tenant := range s.knownTenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
 func (s *TenantDiscoverer) start(ctx context.Context) error {
 	b := backoff.New(ctx, s.userDiscoveryBackoff)
 	var err error
@@ -84,7 +107,7 @@ func (s *TenantDiscoverer) iter(ctx context.Context) error {
 }
 
 func (s *TenantDiscoverer) discoverTenants(ctx context.Context) error {
-	tenants, err := mimir_tsdb.ListUsers(ctx, s.bkt)
+	tenants, err := s.source.Tenants(ctx)
 	if err != nil {
 		level.Warn(s.logger).Log("msg", "failed tenant discovery", "err", err)
 		return err
@@ -99,7 +122,9 @@ func (s *TenantDiscoverer) discoverTenants(ctx context.Context) error {
 
 		seen[tenant] = struct{}{}
 
+		s.knownTenantsMu.Lock()
 		_, exists := s.knownTenants[tenant]
+		s.knownTenantsMu.Unlock()
 
 		if !exists {
 			// Discovered a new tenant
@@ -109,12 +134,17 @@ func (s *TenantDiscoverer) discoverTenants(ctx context.Context) error {
 				continue
 			}
 			tracker := NewJobTracker(persister, tenant, s.clock, s.maxLeases, s.metrics.newTrackerMetricsForTenant(tenant))
+			tracker.SetOnPending(func() { s.rotator.NotifyTenant(tenant) })
 			s.rotator.AddTenant(tenant, tracker)
+			s.knownTenantsMu.Lock()
 			s.knownTenants[tenant] = struct{}{}
+			s.knownTenantsMu.Unlock()
+			// Wake any AcquireJob calls parked waiting for this tenant to appear.
+			s.rotator.NotifyTenant(tenant)
 		}
 	}
 
-	for tenant := range s.knownTenants {
+	for _, tenant := range s.Tenants() {
 		if _, ok := seen[tenant]; !ok {
 			logger := log.With(s.logger, "user", tenant)
 			tracker, ok := s.rotator.RemoveTenant(tenant)
@@ -130,7 +160,9 @@ func (s *TenantDiscoverer) discoverTenants(ctx context.Context) error {
 				}
 				continue
 			}
+			s.knownTenantsMu.Lock()
 			delete(s.knownTenants, tenant)
+			s.knownTenantsMu.Unlock()
 			level.Info(logger).Log("msg", "removed empty tenant from compactor scheduler")
 		}
 	}
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/kv"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+// TenantSource discovers the set of tenants the compactor scheduler should
+// track. TenantDiscoverer calls Tenants on every tenantDiscoveryInterval tick
+// and reconciles the result against the tenants it already knows about.
+type TenantSource interface {
+	Tenants(ctx context.Context) ([]string, error)
+}
+
+// ObjectStoreTenantSource lists tenants straight from the bucket, the way
+// TenantDiscoverer always did before sources became pluggable. It needs no
+// other infrastructure, so it remains the default and the usual
+// reconciliation backstop for the faster sources below.
+type ObjectStoreTenantSource struct {
+	bkt objstore.Bucket
+}
+
+func NewObjectStoreTenantSource(bkt objstore.Bucket) *ObjectStoreTenantSource {
+	return &ObjectStoreTenantSource{bkt: bkt}
+}
+
+func (s *ObjectStoreTenantSource) Tenants(ctx context.Context) ([]string, error) {
+	return mimir_tsdb.ListUsers(ctx, s.bkt)
+}
+
+// BucketIndexTenantSource is a fast path for confirming tenants the scheduler
+// already knows about are still present: instead of listing the whole bucket
+// on every tenantDiscoveryInterval tick, it does a single cheap Attributes
+// (HEAD-style) call per already-known tenant's bucket-index object. It never
+// discovers brand-new tenants on its own - pair it with a slower backstop
+// source (typically ObjectStoreTenantSource, via CompositeTenantSource) that
+// does full bucket listings on a longer interval.
+//
+// A tenant whose bucket-index object can't be found is only treated as
+// deleted once it has been missing for longer than maxStale, to tolerate the
+// window between a tenant's last block being removed and its bucket-index
+// catching up.
+type BucketIndexTenantSource struct {
+	bkt          bucketIndexBucket
+	knownTenants func() []string
+	maxStale     time.Duration
+	logger       log.Logger
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+// bucketIndexBucket is the subset of objstore.Bucket BucketIndexTenantSource
+// needs, narrowed so tests can fake it without standing up a real bucket.
+type bucketIndexBucket interface {
+	Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error)
+	IsObjNotFoundErr(err error) bool
+}
+
+// NewBucketIndexTenantSource returns a BucketIndexTenantSource that confirms
+// the tenants returned by knownTenants at the time of each Tenants call.
+func NewBucketIndexTenantSource(bkt bucketIndexBucket, knownTenants func() []string, maxStale time.Duration, logger log.Logger) *BucketIndexTenantSource {
+	return &BucketIndexTenantSource{
+		bkt:          bkt,
+		knownTenants: knownTenants,
+		maxStale:     maxStale,
+		logger:       logger,
+		lastSeen:     make(map[string]time.Time),
+	}
+}
+
+func (s *BucketIndexTenantSource) Tenants(ctx context.Context) ([]string, error) {
+	known := s.knownTenants()
+	now := time.Now()
+
+	tenants := make([]string, 0, len(known))
+	for _, tenant := range known {
+		attrs, err := s.bkt.Attributes(ctx, path.Join(tenant, bucketindex.IndexCompressedFilename))
+		if err == nil {
+			s.mu.Lock()
+			s.lastSeen[tenant] = now
+			s.mu.Unlock()
+			tenants = append(tenants, tenant)
+			continue
+		}
+
+		if !s.bkt.IsObjNotFoundErr(err) {
+			level.Warn(s.logger).Log("msg", "failed checking bucket index during tenant discovery", "user", tenant, "err", err)
+			tenants = append(tenants, tenant)
+			continue
+		}
+
+		s.mu.Lock()
+		lastSeen, ok := s.lastSeen[tenant]
+		s.mu.Unlock()
+		if !ok || (s.maxStale > 0 && now.Sub(lastSeen) <= s.maxStale) {
+			// Never confirmed present, or still within the grace period since
+			// it last was: keep it rather than treating a lagging bucket-index
+			// as tenant deletion.
+			tenants = append(tenants, tenant)
+		}
+	}
+	return tenants, nil
+}
+
+// TenantSet is the value ingesters publish to the KV store backing
+// KVTenantSource: the full set of tenants they currently know about.
+type TenantSet struct {
+	Tenants []string
+}
+
+// errKVTenantSourceNotReady is returned by KVTenantSource.Tenants before the
+// watch has received its first value, so TenantDiscoverer's reconciliation
+// (which treats "missing from the returned list" as "tenant removed") never
+// runs against a spuriously-empty set right after the process starts.
+var errKVTenantSourceNotReady = errors.New("kv tenant source: no tenant set received yet")
+
+// kvWatcher is the subset of kv.Client KVTenantSource needs, narrowed so
+// tests can fake it without standing up a real KV backend.
+type kvWatcher interface {
+	WatchKey(ctx context.Context, key string, f func(interface{}) bool)
+}
+
+// KVTenantSource consumes tenant membership published by ingesters to a
+// memberlist/KV-backed key, so a newly-created tenant becomes schedulable
+// within seconds instead of waiting a full discovery interval.
+type KVTenantSource struct {
+	client kvWatcher
+	key    string
+
+	mu      sync.Mutex
+	ready   bool
+	tenants map[string]struct{}
+}
+
+// NewKVTenantSource starts watching key on client for tenant set updates.
+// Watching runs for the lifetime of the process; there is no Close because
+// the scheduler itself is expected to live for the process's lifetime too.
+func NewKVTenantSource(client kv.Client, key string, logger log.Logger) *KVTenantSource {
+	s := &KVTenantSource{client: client, key: key, tenants: make(map[string]struct{})}
+	go s.watch(logger)
+	return s
+}
+
+func (s *KVTenantSource) watch(logger log.Logger) {
+	s.client.WatchKey(context.Background(), s.key, func(value interface{}) bool {
+		set, ok := value.(*TenantSet)
+		if !ok {
+			level.Warn(logger).Log("msg", "unexpected value type watching compactor scheduler tenant set", "key", s.key)
+			return true
+		}
+
+		tenants := make(map[string]struct{}, len(set.Tenants))
+		for _, t := range set.Tenants {
+			tenants[t] = struct{}{}
+		}
+
+		s.mu.Lock()
+		s.tenants = tenants
+		s.ready = true
+		s.mu.Unlock()
+		return true
+	})
+}
+
+func (s *KVTenantSource) Tenants(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ready {
+		return nil, errKVTenantSourceNotReady
+	}
+
+	tenants := make([]string, 0, len(s.tenants))
+	for t := range s.tenants {
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
+
+// CompositeTenantSource combines a fast primary source with a slower backstop
+// that's only consulted once per backstopInterval, so operators can run e.g.
+// KVTenantSource as primary with ObjectStoreTenantSource as a periodic
+// reconciliation backstop against missed KV events.
+type CompositeTenantSource struct {
+	primary          TenantSource
+	backstop         TenantSource
+	backstopInterval time.Duration
+	clock            clock.Clock
+
+	mu             sync.Mutex
+	lastBackstopAt time.Time
+}
+
+func NewCompositeTenantSource(primary, backstop TenantSource, backstopInterval time.Duration) *CompositeTenantSource {
+	return &CompositeTenantSource{
+		primary:          primary,
+		backstop:         backstop,
+		backstopInterval: backstopInterval,
+		clock:            clock.New(),
+	}
+}
+
+func (s *CompositeTenantSource) Tenants(ctx context.Context) ([]string, error) {
+	tenants, err := s.primary.Tenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	// The very first call always runs the backstop, zero value or not: a
+	// bucket-index primary only confirms tenants it's already told about, so
+	// on a cold start that's an empty set forever until the backstop seeds
+	// it. Every call after that runs on backstopInterval as normal.
+	due := s.lastBackstopAt.IsZero() || (s.backstopInterval > 0 && s.clock.Now().Sub(s.lastBackstopAt) >= s.backstopInterval)
+	s.mu.Unlock()
+	if !due {
+		return tenants, nil
+	}
+
+	backstopTenants, err := s.backstop.Tenants(ctx)
+	if err != nil {
+		// A failing backstop shouldn't block discovery via the primary; we'll
+		// just try the backstop again on the next due tick.
+		return tenants, nil
+	}
+
+	s.mu.Lock()
+	s.lastBackstopAt = s.clock.Now()
+	s.mu.Unlock()
+
+	seen := make(map[string]struct{}, len(tenants))
+	for _, t := range tenants {
+		seen[t] = struct{}{}
+	}
+	merged := append([]string(nil), tenants...)
+	for _, t := range backstopTenants {
+		if _, ok := seen[t]; !ok {
+			merged = append(merged, t)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/objstore"
+)
+
+const (
+	tenantDiscoveryBackendObjectStore  = "object-store"
+	tenantDiscoveryBackendBucketIndex  = "bucket-index"
+	tenantDiscoveryBackendKV           = "kv"
+	tenantSetKVKey                     = "compactor-scheduler-tenants"
+	defaultBucketIndexMaxStale         = 15 * time.Minute
+	defaultTenantDiscoveryBackstopTick = time.Hour
+)
+
+// TenantDiscoverySourceConfig lets operators pick, and where applicable
+// compose, the TenantSource the compactor scheduler's tenant discoverer uses.
+// "bucket-index" and "kv" are both fast paths that are automatically paired
+// with an object-store backstop (via CompositeTenantSource) so a missed event
+// or a lagging bucket-index can never permanently hide a tenant.
+type TenantDiscoverySourceConfig struct {
+	Backend             string        `yaml:"backend" category:"experimental"`
+	BucketIndexMaxStale time.Duration `yaml:"bucket_index_max_stale" category:"experimental"`
+	BackstopInterval    time.Duration `yaml:"backstop_interval" category:"experimental"`
+	KV                  kv.Config     `yaml:"kv"`
+}
+
+func (cfg *TenantDiscoverySourceConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Backend, prefix+"tenant-discovery.backend", tenantDiscoveryBackendObjectStore,
+		fmt.Sprintf("Backend used to discover tenants for the compactor scheduler. Supported values are: %s, %s, %s.",
+			tenantDiscoveryBackendObjectStore, tenantDiscoveryBackendBucketIndex, tenantDiscoveryBackendKV))
+	f.DurationVar(&cfg.BucketIndexMaxStale, prefix+"tenant-discovery.bucket-index-max-stale", defaultBucketIndexMaxStale,
+		"Only used when the backend is bucket-index. How long a tenant's bucket-index can fail to confirm its presence before the tenant is treated as deleted.")
+	f.DurationVar(&cfg.BackstopInterval, prefix+"tenant-discovery.backstop-interval", defaultTenantDiscoveryBackstopTick,
+		"Only used when the backend is bucket-index or kv. How often to run a full object-store listing as a backstop against missed updates from the faster backend.")
+	cfg.KV.RegisterFlagsWithPrefix(prefix+"tenant-discovery.kv.", "", f)
+}
+
+// NewTenantSource constructs the TenantSource selected by cfg.Backend.
+// knownTenants is only used by the bucket-index backend, to know which
+// tenants' bucket-index objects to check; it should return the tenant
+// discoverer's current known tenant set.
+func NewTenantSource(cfg TenantDiscoverySourceConfig, bkt objstore.Bucket, knownTenants func() []string, reg prometheus.Registerer, logger log.Logger) (TenantSource, error) {
+	objectStore := NewObjectStoreTenantSource(bkt)
+
+	switch cfg.Backend {
+	case "", tenantDiscoveryBackendObjectStore:
+		return objectStore, nil
+	case tenantDiscoveryBackendBucketIndex:
+		primary := NewBucketIndexTenantSource(bkt, knownTenants, cfg.BucketIndexMaxStale, logger)
+		return NewCompositeTenantSource(primary, objectStore, cfg.BackstopInterval), nil
+	case tenantDiscoveryBackendKV:
+		client, err := kv.NewClient(cfg.KV, tenantSetCodec{}, reg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("creating compactor scheduler tenant discovery KV client: %w", err)
+		}
+		primary := NewKVTenantSource(client, tenantSetKVKey, logger)
+		return NewCompositeTenantSource(primary, objectStore, cfg.BackstopInterval), nil
+	default:
+		return nil, fmt.Errorf("unsupported compactor scheduler tenant discovery backend %q", cfg.Backend)
+	}
+}
+
+// tenantSetCodec (de)serializes TenantSet for the KV store backing
+// KVTenantSource.
+type tenantSetCodec struct{}
+
+func (tenantSetCodec) CodecID() string { return "compactorSchedulerTenantSet" }
+
+func (tenantSetCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (tenantSetCodec) Decode(data []byte) (interface{}, error) {
+	var set TenantSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
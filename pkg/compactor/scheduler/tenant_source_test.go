@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+type staticTenantSource struct {
+	tenants []string
+	err     error
+	calls   int
+}
+
+func (s *staticTenantSource) Tenants(context.Context) ([]string, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.tenants, nil
+}
+
+func TestCompositeTenantSource(t *testing.T) {
+	t.Run("runs the backstop on the very first call regardless of interval", func(t *testing.T) {
+		primary := &staticTenantSource{tenants: []string{"a"}}
+		backstop := &staticTenantSource{tenants: []string{"a", "b"}}
+		clk := clock.NewMock()
+
+		s := NewCompositeTenantSource(primary, backstop, time.Hour)
+		s.clock = clk
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a", "b"}, tenants)
+		require.Equal(t, 1, backstop.calls, "a cold start must run the backstop immediately, not wait out a full interval with zero tenants")
+
+		// Not due again immediately after.
+		tenants, err = s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+		require.Equal(t, 1, backstop.calls)
+	})
+
+	t.Run("merges in the backstop once it becomes due again", func(t *testing.T) {
+		primary := &staticTenantSource{tenants: []string{"a"}}
+		backstop := &staticTenantSource{tenants: []string{"a", "b"}}
+		clk := clock.NewMock()
+
+		s := NewCompositeTenantSource(primary, backstop, time.Hour)
+		s.clock = clk
+
+		_, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, backstop.calls)
+
+		clk.Add(time.Hour)
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a", "b"}, tenants)
+		require.Equal(t, 2, backstop.calls)
+	})
+
+	t.Run("a failing backstop doesn't fail discovery via the primary", func(t *testing.T) {
+		primary := &staticTenantSource{tenants: []string{"a"}}
+		backstop := &staticTenantSource{err: errors.New("boom")}
+		clk := clock.NewMock()
+
+		s := NewCompositeTenantSource(primary, backstop, time.Hour)
+		s.clock = clk
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+	})
+
+	t.Run("a failing primary fails discovery outright", func(t *testing.T) {
+		primary := &staticTenantSource{err: errors.New("boom")}
+		backstop := &staticTenantSource{tenants: []string{"a"}}
+
+		s := NewCompositeTenantSource(primary, backstop, time.Hour)
+
+		_, err := s.Tenants(context.Background())
+		require.Error(t, err)
+	})
+}
+
+// fakeBucketIndexBucket lets tests drive BucketIndexTenantSource's
+// Attributes/IsObjNotFoundErr calls without a real bucket.
+type fakeBucketIndexBucket struct {
+	notFound map[string]bool
+	err      error
+}
+
+var errObjNotFound = errors.New("object not found")
+
+func (b *fakeBucketIndexBucket) Attributes(_ context.Context, name string) (objstore.ObjectAttributes, error) {
+	if b.notFound[name] {
+		return objstore.ObjectAttributes{}, errObjNotFound
+	}
+	if b.err != nil {
+		return objstore.ObjectAttributes{}, b.err
+	}
+	return objstore.ObjectAttributes{}, nil
+}
+
+func (b *fakeBucketIndexBucket) IsObjNotFoundErr(err error) bool {
+	return errors.Is(err, errObjNotFound)
+}
+
+func TestBucketIndexTenantSource(t *testing.T) {
+	t.Run("keeps a tenant whose bucket index is found, and records it as seen", func(t *testing.T) {
+		bkt := &fakeBucketIndexBucket{}
+		s := NewBucketIndexTenantSource(bkt, func() []string { return []string{"a"} }, time.Minute, log.NewNopLogger())
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+		require.Contains(t, s.lastSeen, "a")
+	})
+
+	t.Run("keeps a tenant never confirmed present rather than treat it as deleted", func(t *testing.T) {
+		bkt := &fakeBucketIndexBucket{notFound: map[string]bool{path.Join("a", bucketindex.IndexCompressedFilename): true}}
+		s := NewBucketIndexTenantSource(bkt, func() []string { return []string{"a"} }, time.Minute, log.NewNopLogger())
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+	})
+
+	t.Run("keeps a tenant whose bucket index is missing but still within the stale grace period", func(t *testing.T) {
+		bkt := &fakeBucketIndexBucket{notFound: map[string]bool{path.Join("a", bucketindex.IndexCompressedFilename): true}}
+		s := NewBucketIndexTenantSource(bkt, func() []string { return []string{"a"} }, time.Minute, log.NewNopLogger())
+		s.lastSeen["a"] = time.Now().Add(-10 * time.Second)
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+	})
+
+	t.Run("drops a tenant whose bucket index has been missing for longer than maxStale", func(t *testing.T) {
+		bkt := &fakeBucketIndexBucket{notFound: map[string]bool{path.Join("a", bucketindex.IndexCompressedFilename): true}}
+		s := NewBucketIndexTenantSource(bkt, func() []string { return []string{"a"} }, time.Minute, log.NewNopLogger())
+		s.lastSeen["a"] = time.Now().Add(-time.Hour)
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, tenants)
+	})
+
+	t.Run("keeps a tenant on an unexpected error rather than treat it as deleted", func(t *testing.T) {
+		bkt := &fakeBucketIndexBucket{err: errors.New("boom")}
+		s := NewBucketIndexTenantSource(bkt, func() []string { return []string{"a"} }, time.Minute, log.NewNopLogger())
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+	})
+}
+
+// fakeKVWatcher lets tests drive KVTenantSource's watch callback directly,
+// without a real KV backend.
+type fakeKVWatcher struct {
+	f func(interface{}) bool
+}
+
+func (w *fakeKVWatcher) WatchKey(_ context.Context, _ string, f func(interface{}) bool) {
+	w.f = f
+}
+
+func (w *fakeKVWatcher) push(v interface{}) {
+	w.f(v)
+}
+
+func TestKVTenantSource(t *testing.T) {
+	t.Run("not ready before the first value arrives", func(t *testing.T) {
+		watcher := &fakeKVWatcher{}
+		s := &KVTenantSource{client: watcher, key: "tenants", tenants: make(map[string]struct{})}
+		go s.watch(log.NewNopLogger())
+
+		_, err := s.Tenants(context.Background())
+		require.ErrorIs(t, err, errKVTenantSourceNotReady)
+	})
+
+	t.Run("reports the tenant set once a value arrives", func(t *testing.T) {
+		watcher := &fakeKVWatcher{}
+		s := &KVTenantSource{client: watcher, key: "tenants", tenants: make(map[string]struct{})}
+		go s.watch(log.NewNopLogger())
+		require.Eventually(t, func() bool { return watcher.f != nil }, time.Second, time.Millisecond)
+
+		watcher.push(&TenantSet{Tenants: []string{"a", "b"}})
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a", "b"}, tenants)
+	})
+
+	t.Run("ignores a malformed value and keeps the last good set", func(t *testing.T) {
+		watcher := &fakeKVWatcher{}
+		s := &KVTenantSource{client: watcher, key: "tenants", tenants: make(map[string]struct{})}
+		go s.watch(log.NewNopLogger())
+		require.Eventually(t, func() bool { return watcher.f != nil }, time.Second, time.Millisecond)
+
+		watcher.push(&TenantSet{Tenants: []string{"a"}})
+		watcher.push("not a tenant set")
+
+		tenants, err := s.Tenants(context.Background())
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, tenants)
+	})
+}